@@ -1,9 +1,10 @@
 package cqrs
 
 import (
+	"context"
 	"errors"
-	"log"
 	"reflect"
+	"sync"
 	"time"
 )
 
@@ -50,6 +51,7 @@ type VersionedEventDispatchManager struct {
 	versionedEventDispatcher *MapBasedVersionedEventDispatcher
 	typeRegistry             TypeRegistry
 	receiver                 VersionedEventReceiver
+	publicationLogger        VersionedEventPublicationLogger
 }
 
 // VersionedEventTransactedAccept is the message routed from an event receiver to the event manager.
@@ -62,7 +64,7 @@ type VersionedEventTransactedAccept struct {
 // VersionedEventReceiverOptions is an initalization structure to communicate to and from an event receiver go routine
 type VersionedEventReceiverOptions struct {
 	TypeRegistry TypeRegistry
-	Close        chan chan error
+	Context      context.Context
 	Error        chan error
 	ReceiveEvent chan VersionedEventTransactedAccept
 	Exclusive    bool
@@ -72,26 +74,59 @@ type VersionedEventReceiverOptions struct {
 type VersionedEventDispatcher interface {
 	DispatchEvent(VersionedEvent) error
 	RegisterEventHandler(event interface{}, handler VersionedEventHandler)
-	RegisterGlobalHandler(handler VersionedEventHandler)
+	RegisterEventHandlerPattern(pattern string, handler VersionedEventHandler) error
+	RegisterGlobalHandler(handler VersionedEventHandler) uint64
+	RemoveGlobalHandler(id uint64)
+}
+
+// globalHandlerRegistration pairs a global handler with the id RegisterGlobalHandler returned for it, so
+// RemoveGlobalHandler can find and deregister it again.
+type globalHandlerRegistration struct {
+	id      uint64
+	handler VersionedEventHandler
 }
 
 // MapBasedVersionedEventDispatcher is a simple implementation of the versioned event dispatcher. Using a map it registered event handlers to event types
+//
+// mu guards registry, patternHandlers, globalHandlers and middleware: callers like Subscribe register and
+// remove global handlers for the lifetime of a long-running subscription, concurrently with DispatchEvent
+// running in the Listen/ListenContext loop, so reads and writes to this state must be synchronized.
 type MapBasedVersionedEventDispatcher struct {
-	registry       map[reflect.Type][]VersionedEventHandler
-	globalHandlers []VersionedEventHandler
+	mu                  sync.RWMutex
+	registry            map[reflect.Type][]VersionedEventHandler
+	patternHandlers     []eventPatternHandler
+	globalHandlers      []globalHandlerRegistration
+	nextGlobalHandlerID uint64
+	middleware          []VersionedEventMiddleware
 }
 
 // VersionedEventHandler is a function that takes a versioned event
 type VersionedEventHandler func(VersionedEvent) error
 
+// VersionedEventMiddleware wraps a VersionedEventHandler to add cross-cutting behaviour (retries,
+// timeouts, metrics, panic recovery, ...) without the handler itself needing to know about it.
+type VersionedEventMiddleware func(VersionedEventHandler) VersionedEventHandler
+
 // NewVersionedEventDispatcher is a constructor for the MapBasedVersionedEventDispatcher
 func NewVersionedEventDispatcher() *MapBasedVersionedEventDispatcher {
-	registry := make(map[reflect.Type][]VersionedEventHandler)
-	return &MapBasedVersionedEventDispatcher{registry, []VersionedEventHandler{}}
+	return &MapBasedVersionedEventDispatcher{registry: make(map[reflect.Type][]VersionedEventHandler)}
+}
+
+// Use appends middleware to the dispatcher's chain. Every handler invocation - exact-type, pattern and
+// global alike - is wrapped by the full chain in registration order, so the first middleware passed to
+// Use is the outermost wrapper and runs first.
+func (m *MapBasedVersionedEventDispatcher) Use(middleware ...VersionedEventMiddleware) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.middleware = append(m.middleware, middleware...)
 }
 
 // RegisterEventHandler allows a caller to register an event handler given an event of the specified type being received
 func (m *MapBasedVersionedEventDispatcher) RegisterEventHandler(event interface{}, handler VersionedEventHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	eventType := reflect.TypeOf(event)
 	handlers, ok := m.registry[eventType]
 	if ok {
@@ -101,24 +136,84 @@ func (m *MapBasedVersionedEventDispatcher) RegisterEventHandler(event interface{
 	}
 }
 
-// RegisterGlobalHandler allows a caller to register a wildcard event handler call on any event received
-func (m *MapBasedVersionedEventDispatcher) RegisterGlobalHandler(handler VersionedEventHandler) {
-	m.globalHandlers = append(m.globalHandlers, handler)
+// RegisterEventHandlerPattern allows a caller to subscribe a handler to every event whose EventType matches
+// pattern, e.g. "order.*", "*.Created" or "billing.**". `*` matches a single dot-separated segment, `**`
+// matches across segments and `?` matches a single character. The pattern is compiled once here; a
+// malformed pattern is rejected at registration time rather than failing silently on every dispatch.
+func (m *MapBasedVersionedEventDispatcher) RegisterEventHandlerPattern(pattern string, handler VersionedEventHandler) error {
+	matcher, err := CompileEventPattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.patternHandlers = append(m.patternHandlers, eventPatternHandler{pattern, matcher, handler})
+	return nil
+}
+
+// RegisterGlobalHandler allows a caller to register a wildcard event handler call on any event received.
+// The returned id can later be passed to RemoveGlobalHandler to deregister it.
+func (m *MapBasedVersionedEventDispatcher) RegisterGlobalHandler(handler VersionedEventHandler) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextGlobalHandlerID++
+	id := m.nextGlobalHandlerID
+	m.globalHandlers = append(m.globalHandlers, globalHandlerRegistration{id, handler})
+	return id
 }
 
-// DispatchEvent executes all event handlers registered for the given event type
+// RemoveGlobalHandler deregisters a handler previously registered with RegisterGlobalHandler. It is a
+// no-op if id is unknown, e.g. because it was already removed.
+func (m *MapBasedVersionedEventDispatcher) RemoveGlobalHandler(id uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, registration := range m.globalHandlers {
+		if registration.id == id {
+			m.globalHandlers = append(m.globalHandlers[:i:i], m.globalHandlers[i+1:]...)
+			return
+		}
+	}
+}
+
+// DispatchEvent executes all event handlers registered for the given event type, then any pattern handlers
+// whose glob matches VersionedEvent.EventType, then the global handlers. An event can fire both an exact
+// type handler and one or more pattern handlers.
 func (m *MapBasedVersionedEventDispatcher) DispatchEvent(event VersionedEvent) error {
-	eventType := reflect.TypeOf(event.Event)
-	if handlers, ok := m.registry[eventType]; ok {
-		for _, handler := range handlers {
-			if err := handler(event); err != nil {
+	m.mu.RLock()
+	handlers := m.registry[reflect.TypeOf(event.Event)]
+	patternHandlers := m.patternHandlers
+	globalHandlers := m.globalHandlers
+	middleware := m.middleware
+	m.mu.RUnlock()
+
+	wrap := func(handler VersionedEventHandler) VersionedEventHandler {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			handler = middleware[i](handler)
+		}
+
+		return handler
+	}
+
+	for _, handler := range handlers {
+		if err := wrap(handler)(event); err != nil {
+			return err
+		}
+	}
+
+	for _, patternHandler := range patternHandlers {
+		if patternHandler.matcher.MatchString(event.EventType) {
+			if err := wrap(patternHandler.handler)(event); err != nil {
 				return err
 			}
 		}
 	}
 
-	for _, handler := range m.globalHandlers {
-		if err := handler(event); err != nil {
+	for _, registration := range globalHandlers {
+		if err := wrap(registration.handler)(event); err != nil {
 			return err
 		}
 	}
@@ -128,7 +223,13 @@ func (m *MapBasedVersionedEventDispatcher) DispatchEvent(event VersionedEvent) e
 
 // NewVersionedEventDispatchManager is a constructor for the VersionedEventDispatchManager
 func NewVersionedEventDispatchManager(receiver VersionedEventReceiver, registry TypeRegistry) *VersionedEventDispatchManager {
-	return &VersionedEventDispatchManager{NewVersionedEventDispatcher(), registry, receiver}
+	return &VersionedEventDispatchManager{NewVersionedEventDispatcher(), registry, receiver, nil}
+}
+
+// SetPublicationLogger attaches a VersionedEventPublicationLogger backing replay for Subscribe. Without
+// one, Subscribe calls with SubscribeOptions.Replay set only ever see live events.
+func (m *VersionedEventDispatchManager) SetPublicationLogger(publicationLogger VersionedEventPublicationLogger) {
+	m.publicationLogger = publicationLogger
 }
 
 // RegisterEventHandler allows a caller to register an event handler given an event of the specified type being received
@@ -137,24 +238,42 @@ func (m *VersionedEventDispatchManager) RegisterEventHandler(event interface{},
 	m.versionedEventDispatcher.RegisterEventHandler(event, handler)
 }
 
-// RegisterGlobalHandler allows a caller to register a wildcard event handler call on any event received
-func (m *VersionedEventDispatchManager) RegisterGlobalHandler(handler VersionedEventHandler) {
-	m.versionedEventDispatcher.RegisterGlobalHandler(handler)
+// RegisterEventHandlerPattern allows a caller to subscribe a handler to every event whose EventType matches
+// a glob pattern, e.g. "order.*", "*.Created" or "billing.**"
+func (m *VersionedEventDispatchManager) RegisterEventHandlerPattern(pattern string, handler VersionedEventHandler) error {
+	return m.versionedEventDispatcher.RegisterEventHandlerPattern(pattern, handler)
 }
 
-// Listen starts a listen loop processing channels related to new incoming events, errors and stop listening requests
-func (m *VersionedEventDispatchManager) Listen(stop <-chan bool, exclusive bool) error {
-	// Create communication channels
-	//
-	// for closing the queue listener,
-	closeChannel := make(chan chan error)
+// RegisterGlobalHandler allows a caller to register a wildcard event handler call on any event received.
+// The returned id can later be passed to RemoveGlobalHandler to deregister it.
+func (m *VersionedEventDispatchManager) RegisterGlobalHandler(handler VersionedEventHandler) uint64 {
+	return m.versionedEventDispatcher.RegisterGlobalHandler(handler)
+}
+
+// RemoveGlobalHandler deregisters a handler previously registered with RegisterGlobalHandler.
+func (m *VersionedEventDispatchManager) RemoveGlobalHandler(id uint64) {
+	m.versionedEventDispatcher.RemoveGlobalHandler(id)
+}
+
+// Use appends middleware to the dispatcher's chain, wrapping every registered and future handler -
+// exact-type, pattern and global alike - in registration order.
+func (m *VersionedEventDispatchManager) Use(middleware ...VersionedEventMiddleware) {
+	m.versionedEventDispatcher.Use(middleware...)
+}
+
+// ListenContext starts a listen loop processing channels related to new incoming events and errors, driven by
+// ctx instead of a stop channel. ctx is handed to the receiver as VersionedEventReceiverOptions.Context, so
+// cancelling it also tears the receiver down. An event already being dispatched when ctx is cancelled is
+// dispatched to the end, but its acknowledgement may not be sent once ctx is done. ListenContext then returns
+// ctx.Err() straight away; it does not wait for the receiver's go routine to exit.
+func (m *VersionedEventDispatchManager) ListenContext(ctx context.Context, exclusive bool) error {
 	// receiving errors from the listener thread (go routine)
 	errorChannel := make(chan error)
 	// and receiving events from the queue
 	receiveEventChannel := make(chan VersionedEventTransactedAccept)
 
 	// Start receiving events by passing these channels to the worker thread (go routine)
-	options := VersionedEventReceiverOptions{m.typeRegistry, closeChannel, errorChannel, receiveEventChannel, exclusive}
+	options := VersionedEventReceiverOptions{m.typeRegistry, ctx, errorChannel, receiveEventChannel, exclusive}
 	if err := m.receiver.ReceiveEvents(options); err != nil {
 		return err
 	}
@@ -165,23 +284,57 @@ func (m *VersionedEventDispatchManager) Listen(stop <-chan bool, exclusive bool)
 		// Version event received channel receives a result with a channel to respond to, signifying successful processing of the message.
 		// This should eventually call an event handler. See cqrs.NewVersionedEventDispatcher()
 		case event := <-receiveEventChannel:
-			log.Println("EventDispatchManager.DispatchEvent: ", event.Event)
+			fields := map[string]interface{}{
+				"event_type":     event.Event.EventType,
+				"source_id":      event.Event.SourceID,
+				"version":        event.Event.Version,
+				"correlation_id": event.Event.CorrelationID,
+			}
+
+			logger().Debug("EventDispatchManager.DispatchEvent", fields)
+			success := true
 			if err := m.versionedEventDispatcher.DispatchEvent(event.Event); err != nil {
-				log.Println("Error dispatching event: ", err)
+				success = false
+				logger().Error("EventDispatchManager.DispatchEvent failed", fields, err)
 			}
 
-			event.ProcessedSuccessfully <- true
-			log.Println("EventDispatchManager.DispatchSuccessful")
-		case <-stop:
-			log.Println("EventDispatchManager.Stopping")
-			closeSignal := make(chan error)
-			closeChannel <- closeSignal
-			defer log.Println("EventDispatchManager.Stopped")
-			return <-closeSignal
+			select {
+			case event.ProcessedSuccessfully <- success:
+			case <-ctx.Done():
+			}
+
+			logger().Debug("EventDispatchManager.DispatchSuccessful", fields)
+		case <-ctx.Done():
+			logger().Info("EventDispatchManager.Stopping", nil)
+			defer logger().Info("EventDispatchManager.Stopped", nil)
+			return ctx.Err()
 		// Receiving on this channel signifys an error has occured worker processor side
 		case err := <-errorChannel:
-			log.Println("EventDispatchManager.ErrorReceived: ", err)
+			logger().Error("EventDispatchManager.ErrorReceived", nil, err)
 			return err
 		}
 	}
 }
+
+// Listen starts a listen loop processing channels related to new incoming events, errors and stop listening requests
+//
+// Deprecated: use ListenContext, which replaces the stop channel with context cancellation and passes the
+// context's deadline and values on to the receiver. Event handlers are not given the context.
+func (m *VersionedEventDispatchManager) Listen(stop <-chan bool, exclusive bool) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := m.ListenContext(ctx, exclusive); err != nil && err != context.Canceled {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+// Package httpsse provides a cqrs.VersionedEventPublisher / cqrs.VersionedEventReceiver pair backed by
+// HTTP Server-Sent Events, so a single process can publish VersionedEvents over text/event-stream and any
+// number of remote processes can subscribe without a message broker in between.
+package httpsse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/andrewwebber/cqrs"
+)
+
+// ErrSSESubscriberQueueFull is returned by PublishEvents when a connection's queue is full and the
+// publisher was configured with the cqrs.ReturnError overflow policy.
+var ErrSSESubscriberQueueFull = errors.New("cqrs/httpsse: subscriber queue full")
+
+// sseSubscriber is a single connected SSE client, optionally filtered to a subset of event types. done is
+// closed by ServeHTTP when the connection ends and is never the events channel itself, so a PublishEvents
+// call already blocked delivering to this subscriber aborts instead of blocking forever on a client that
+// has stopped draining.
+type sseSubscriber struct {
+	events  chan cqrs.VersionedEvent
+	done    chan struct{}
+	pattern *regexp.Regexp
+}
+
+// SSEPublisherOptions configures a NewSSEEventPublisherWithOptions instance.
+type SSEPublisherOptions struct {
+	// QueueSize is the per-connection buffer size for events awaiting delivery over the stream. Zero
+	// means unbuffered.
+	QueueSize int
+	// OverflowPolicy controls what happens when a connection's queue is full. The zero value is
+	// cqrs.Block. PublishEvents fans every event out to every connection in the same call, so under
+	// cqrs.Block a single HTTP client that stops draining (a slow browser tab, a dead TCP peer that hasn't
+	// timed out yet, ...) stalls delivery to every other subscriber; NewSSEEventPublisher therefore uses
+	// cqrs.DropOldest, unlike NewInMemoryEventBus.
+	OverflowPolicy cqrs.EventBusOverflowPolicy
+}
+
+// SSEEventPublisher implements cqrs.VersionedEventPublisher and http.Handler, broadcasting every
+// published event as a Server-Sent Events frame to every currently connected client.
+type SSEEventPublisher struct {
+	publicationLogger cqrs.VersionedEventPublicationLogger
+	options           SSEPublisherOptions
+
+	mu          sync.Mutex
+	subscribers map[*sseSubscriber]struct{}
+}
+
+// NewSSEEventPublisher constructs an SSEEventPublisher with a 64-event per-connection buffer and the
+// DropOldest overflow policy. publicationLogger is optional; when provided it backs Last-Event-ID resume
+// by replaying AllIntegrationEventsEverPublished newer than the requested id before a connection switches
+// over to live events.
+func NewSSEEventPublisher(publicationLogger cqrs.VersionedEventPublicationLogger) *SSEEventPublisher {
+	return NewSSEEventPublisherWithOptions(publicationLogger, SSEPublisherOptions{QueueSize: 64, OverflowPolicy: cqrs.DropOldest})
+}
+
+// NewSSEEventPublisherWithOptions constructs an SSEEventPublisher with explicit per-connection queueing
+// and overflow behaviour. See NewSSEEventPublisher for the defaults most callers want.
+func NewSSEEventPublisherWithOptions(publicationLogger cqrs.VersionedEventPublicationLogger, options SSEPublisherOptions) *SSEEventPublisher {
+	return &SSEEventPublisher{publicationLogger: publicationLogger, options: options, subscribers: make(map[*sseSubscriber]struct{})}
+}
+
+// PublishEvents implements cqrs.VersionedEventPublisher by fanning each event out to every connected
+// subscriber whose pattern filter matches its EventType.
+func (p *SSEEventPublisher) PublishEvents(events []cqrs.VersionedEvent) error {
+	p.mu.Lock()
+	subscribers := make([]*sseSubscriber, 0, len(p.subscribers))
+	for sub := range p.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	p.mu.Unlock()
+
+	for _, event := range events {
+		for _, sub := range subscribers {
+			if sub.pattern != nil && !sub.pattern.MatchString(event.EventType) {
+				continue
+			}
+
+			if err := p.deliver(sub, event); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// deliver sends event to a single connection's queue, applying the publisher's configured overflow
+// policy. It always also selects on sub.done, so a connection that has closed makes deliver return
+// cleanly instead of blocking forever or sending to an abandoned channel.
+func (p *SSEEventPublisher) deliver(sub *sseSubscriber, event cqrs.VersionedEvent) error {
+	switch p.options.OverflowPolicy {
+	case cqrs.DropOldest:
+		for {
+			select {
+			case sub.events <- event:
+				return nil
+			case <-sub.done:
+				return nil
+			default:
+				select {
+				case <-sub.events:
+				default:
+				}
+			}
+		}
+	case cqrs.ReturnError:
+		select {
+		case sub.events <- event:
+			return nil
+		case <-sub.done:
+			return nil
+		default:
+			return ErrSSESubscriberQueueFull
+		}
+	default:
+		select {
+		case sub.events <- event:
+			return nil
+		case <-sub.done:
+			return nil
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler, upgrading the request to a text/event-stream connection. An optional
+// ?pattern= query parameter filters which events this connection receives, using the same dot-separated
+// glob syntax as RegisterEventHandlerPattern (`*` matches a single segment, `**` matches across segments,
+// `?` matches a single character) rather than a plain regexp. A Last-Event-ID header (or
+// ?lastEventId= query parameter) replays anything published since that event's ID, via publicationLogger,
+// before the connection switches to live events.
+func (p *SSEEventPublisher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	var pattern *regexp.Regexp
+	if raw := r.URL.Query().Get("pattern"); raw != "" {
+		compiled, err := cqrs.CompileEventPattern(raw)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid pattern: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		pattern = compiled
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+
+	if lastEventID != "" && p.publicationLogger != nil {
+		for _, event := range p.replaySince(lastEventID) {
+			if pattern != nil && !pattern.MatchString(event.EventType) {
+				continue
+			}
+
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+
+	sub := &sseSubscriber{events: make(chan cqrs.VersionedEvent, p.options.QueueSize), done: make(chan struct{}), pattern: pattern}
+
+	p.mu.Lock()
+	p.subscribers[sub] = struct{}{}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.subscribers, sub)
+		p.mu.Unlock()
+		close(sub.done)
+	}()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-sub.events:
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+// replaySince returns every published event newer than lastEventID, ordered by creation time. If
+// lastEventID can't be found (e.g. it has aged out of the log) every published event is replayed.
+func (p *SSEEventPublisher) replaySince(lastEventID string) []cqrs.VersionedEvent {
+	all, err := p.publicationLogger.AllIntegrationEventsEverPublished()
+	if err != nil {
+		return nil
+	}
+
+	sort.Sort(cqrs.ByCreated(all))
+
+	for i, event := range all {
+		if event.ID == lastEventID {
+			return all[i+1:]
+		}
+	}
+
+	return all
+}
+
+// writeEvent serializes event as a single SSE frame: id: VersionedEvent.ID, event: EventType and a
+// data: line carrying the JSON-encoded VersionedEvent.
+func writeEvent(w http.ResponseWriter, event cqrs.VersionedEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.EventType, body)
+	return err
+}
@@ -0,0 +1,179 @@
+package httpsse
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/andrewwebber/cqrs"
+)
+
+// SSEEventReceiver implements cqrs.VersionedEventReceiver by consuming a Server-Sent Events stream
+// published by an SSEEventPublisher (or any compatible endpoint) and routing it to the caller's receive
+// channel with transacted acknowledgement.
+type SSEEventReceiver struct {
+	url string
+
+	// InitialBackoff and MaxBackoff configure the exponential backoff used to reconnect after the stream
+	// drops.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// NewSSEEventReceiver constructs an SSEEventReceiver that streams events from url.
+func NewSSEEventReceiver(url string) *SSEEventReceiver {
+	return &SSEEventReceiver{url: url, InitialBackoff: 250 * time.Millisecond, MaxBackoff: 30 * time.Second}
+}
+
+// ReceiveEvents starts a go routine, named "SSEEventReceiver" in logs via cqrs.ActiveLogger, that connects
+// to the stream and reconstructs each VersionedEvent's concrete Event via options.TypeRegistry before
+// pushing it onto options.ReceiveEvent. The connection is retried with exponential backoff if it drops,
+// resuming from the last delivered event's ID. The go routine exits as soon as options.Context is
+// cancelled.
+func (r *SSEEventReceiver) ReceiveEvents(options cqrs.VersionedEventReceiverOptions) error {
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	go r.listen(ctx, options)
+	return nil
+}
+
+func (r *SSEEventReceiver) listen(ctx context.Context, options cqrs.VersionedEventReceiverOptions) {
+	cqrs.ActiveLogger().Info("SSEEventReceiver: starting", map[string]interface{}{"url": r.url})
+	defer cqrs.ActiveLogger().Info("SSEEventReceiver: stopped", map[string]interface{}{"url": r.url})
+
+	backoff := r.InitialBackoff
+	var lastEventID string
+
+	for ctx.Err() == nil {
+		if err := r.stream(ctx, options, &lastEventID); err != nil {
+			cqrs.ActiveLogger().Error("SSEEventReceiver: stream failed", map[string]interface{}{"url": r.url}, err)
+
+			select {
+			case options.Error <- err:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+
+		if backoff *= 2; backoff > r.MaxBackoff {
+			backoff = r.MaxBackoff
+		}
+	}
+}
+
+// stream opens a single connection and processes frames until it drops or ctx is cancelled, updating
+// lastEventID as events are successfully delivered so a reconnect can resume from where it left off.
+func (r *SSEEventReceiver) stream(ctx context.Context, options cqrs.VersionedEventReceiverOptions, lastEventID *string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+
+	if *lastEventID != "" {
+		req.Header.Set("Last-Event-ID", *lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cqrs/httpsse: unexpected status %s", resp.Status)
+	}
+
+	var id, data string
+	flush := func() error {
+		if data == "" {
+			return nil
+		}
+
+		event, err := decodeEvent(options.TypeRegistry, data)
+		if err != nil {
+			id, data = "", ""
+			return err
+		}
+
+		ackCh := make(chan bool)
+		select {
+		case options.ReceiveEvent <- cqrs.VersionedEventTransactedAccept{Event: event, ProcessedSuccessfully: ackCh}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		select {
+		case <-ackCh:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		*lastEventID = id
+		id, data = "", ""
+		return nil
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := flush(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "id:"):
+			id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "data:"):
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+
+	return scanner.Err()
+}
+
+// decodeEvent unmarshals a VersionedEvent's JSON data: payload and, if registry knows its EventType,
+// re-decodes the Event field into the concrete registered type instead of a generic map.
+func decodeEvent(registry cqrs.TypeRegistry, data string) (cqrs.VersionedEvent, error) {
+	var event cqrs.VersionedEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return event, err
+	}
+
+	if registry == nil {
+		return event, nil
+	}
+
+	instance, ok := registry.GetTypeByName(event.EventType, false)
+	if !ok {
+		return event, nil
+	}
+
+	body, err := json.Marshal(event.Event)
+	if err != nil {
+		return event, nil
+	}
+
+	// instance is a value of the registered concrete type, not a pointer, so unmarshalling into &instance
+	// would just populate a generic map[string]interface{} behind the interface{} and never the concrete
+	// type. Allocate a new pointer of that same type to unmarshal into instead.
+	ptr := reflect.New(reflect.TypeOf(instance)).Interface()
+	if err := json.Unmarshal(body, ptr); err == nil {
+		event.Event = reflect.ValueOf(ptr).Elem().Interface()
+	}
+
+	return event, nil
+}
@@ -0,0 +1,287 @@
+package httpsse_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andrewwebber/cqrs"
+	"github.com/andrewwebber/cqrs/httpsse"
+)
+
+func TestSSEPublisherReceiverRoundTrip(t *testing.T) {
+	publisher := httpsse.NewSSEEventPublisher(nil)
+	server := httptest.NewServer(publisher)
+	defer server.Close()
+
+	receiver := httpsse.NewSSEEventReceiver(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receiveChannel := make(chan cqrs.VersionedEventTransactedAccept)
+	errorChannel := make(chan error, 1)
+
+	options := cqrs.VersionedEventReceiverOptions{Context: ctx, Error: errorChannel, ReceiveEvent: receiveChannel}
+	if err := receiver.ReceiveEvents(options); err != nil {
+		t.Fatal(err)
+	}
+
+	// Give the receiver's GET a moment to connect before the publisher fans anything out.
+	time.Sleep(100 * time.Millisecond)
+
+	published := cqrs.VersionedEvent{ID: "1", EventType: "order.Created", SourceID: "order-1"}
+	if err := publisher.PublishEvents([]cqrs.VersionedEvent{published}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case accept := <-receiveChannel:
+		if accept.Event.ID != published.ID || accept.Event.EventType != published.EventType {
+			t.Fatalf("expected %v, got %v", published, accept.Event)
+		}
+
+		accept.ProcessedSuccessfully <- true
+	case err := <-errorChannel:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the event to arrive over SSE")
+	}
+}
+
+func TestSSEPublisherPatternFiltersUsingCQRSGlobSyntax(t *testing.T) {
+	publisher := httpsse.NewSSEEventPublisher(nil)
+	server := httptest.NewServer(publisher)
+	defer server.Close()
+
+	// "billing.**" is a valid cqrs glob (matches across segments) but not a valid regexp, so this also
+	// confirms the publisher is no longer compiling ?pattern= as a raw regexp.
+	receiver := httpsse.NewSSEEventReceiver(server.URL + "?pattern=billing.**")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receiveChannel := make(chan cqrs.VersionedEventTransactedAccept)
+	errorChannel := make(chan error, 1)
+
+	options := cqrs.VersionedEventReceiverOptions{Context: ctx, Error: errorChannel, ReceiveEvent: receiveChannel}
+	if err := receiver.ReceiveEvents(options); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if err := publisher.PublishEvents([]cqrs.VersionedEvent{{ID: "1", EventType: "order.Created"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	matching := cqrs.VersionedEvent{ID: "2", EventType: "billing.invoice.Created"}
+	if err := publisher.PublishEvents([]cqrs.VersionedEvent{matching}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case accept := <-receiveChannel:
+		if accept.Event.ID != matching.ID {
+			t.Fatalf("expected the filtered event %v, got %v", matching, accept.Event)
+		}
+
+		accept.ProcessedSuccessfully <- true
+	case err := <-errorChannel:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the pattern-matched event to arrive over SSE")
+	}
+}
+
+// blockingResponseWriter is an http.ResponseWriter/http.Flusher test double whose Write blocks until
+// release is closed, simulating an SSE client that has stopped draining its connection (a stalled browser
+// tab, a dead TCP peer that hasn't timed out yet, ...).
+type blockingResponseWriter struct {
+	header  http.Header
+	release chan struct{}
+}
+
+func newBlockingResponseWriter() *blockingResponseWriter {
+	return &blockingResponseWriter{header: make(http.Header), release: make(chan struct{})}
+}
+
+func (w *blockingResponseWriter) Header() http.Header { return w.header }
+func (w *blockingResponseWriter) WriteHeader(int)     {}
+func (w *blockingResponseWriter) Flush()              {}
+
+func (w *blockingResponseWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func TestSSEPublisherStalledSubscriberDoesNotBlockOthers(t *testing.T) {
+	publisher := httpsse.NewSSEEventPublisherWithOptions(nil, httpsse.SSEPublisherOptions{QueueSize: 1, OverflowPolicy: cqrs.DropOldest})
+
+	stalledCtx, stopStalled := context.WithCancel(context.Background())
+	stalledWriter := newBlockingResponseWriter()
+	stalledReq := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(stalledCtx)
+
+	stalledDone := make(chan struct{})
+	go func() {
+		defer close(stalledDone)
+		publisher.ServeHTTP(stalledWriter, stalledReq)
+	}()
+
+	// Give ServeHTTP a moment to register its subscriber. It writes nothing until the first event arrives;
+	// that first Write then blocks.
+	time.Sleep(50 * time.Millisecond)
+
+	// Publish enough events to fill and overflow the stalled subscriber's single-slot queue. The first
+	// event is taken by the blocked Write and the second fills the queue, so under cqrs.Block instead of
+	// DropOldest PublishEvents would block forever from the third call on.
+	for i := 0; i < 5; i++ {
+		event := cqrs.VersionedEvent{ID: fmt.Sprintf("stalled-%d", i)}
+
+		published := make(chan error, 1)
+		go func() { published <- publisher.PublishEvents([]cqrs.VersionedEvent{event}) }()
+
+		select {
+		case err := <-published:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("PublishEvents blocked on a stalled subscriber")
+		}
+	}
+
+	// A second, actively-draining subscriber must still receive events published while the first one is
+	// stalled.
+	server := httptest.NewServer(publisher)
+	defer server.Close()
+
+	receiver := httpsse.NewSSEEventReceiver(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receiveChannel := make(chan cqrs.VersionedEventTransactedAccept)
+	errorChannel := make(chan error, 1)
+	if err := receiver.ReceiveEvents(cqrs.VersionedEventReceiverOptions{Context: ctx, Error: errorChannel, ReceiveEvent: receiveChannel}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	published := cqrs.VersionedEvent{ID: "live", EventType: "order.Created"}
+	if err := publisher.PublishEvents([]cqrs.VersionedEvent{published}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case accept := <-receiveChannel:
+		if accept.Event.ID != published.ID {
+			t.Fatalf("expected %v, got %v", published, accept.Event)
+		}
+
+		accept.ProcessedSuccessfully <- true
+	case err := <-errorChannel:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the live subscriber to receive an event despite the stalled one")
+	}
+
+	close(stalledWriter.release)
+	stopStalled()
+
+	select {
+	case <-stalledDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the stalled subscriber's ServeHTTP to return")
+	}
+}
+
+// orderCreated is a stand-in for a caller's registered event type, used to confirm decodeEvent rebuilds
+// the concrete type rather than leaving Event as a generic map[string]interface{}.
+type orderCreated struct {
+	OrderID string `json:"orderID"`
+}
+
+// mapTypeRegistry is a minimal cqrs.TypeRegistry test double backed by a name -> zero value map.
+type mapTypeRegistry struct {
+	byName map[string]interface{}
+}
+
+func newMapTypeRegistry() *mapTypeRegistry {
+	return &mapTypeRegistry{byName: make(map[string]interface{})}
+}
+
+func (r *mapTypeRegistry) RegisterType(source interface{}) {
+	r.byName[fmt.Sprintf("%T", source)] = source
+}
+
+func (r *mapTypeRegistry) RegisterAliasType(name string, source interface{}) {
+	r.byName[name] = source
+}
+
+func (r *mapTypeRegistry) GetTypeByName(typeName string, alias bool) (interface{}, bool) {
+	source, ok := r.byName[typeName]
+	return source, ok
+}
+
+func TestSSEPublisherReceiverRoundTripDecodesRegisteredType(t *testing.T) {
+	registry := newMapTypeRegistry()
+	registry.RegisterAliasType("order.Created", orderCreated{})
+
+	publisher := httpsse.NewSSEEventPublisher(nil)
+	server := httptest.NewServer(publisher)
+	defer server.Close()
+
+	receiver := httpsse.NewSSEEventReceiver(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	receiveChannel := make(chan cqrs.VersionedEventTransactedAccept)
+	errorChannel := make(chan error, 1)
+
+	options := cqrs.VersionedEventReceiverOptions{
+		Context:      ctx,
+		Error:        errorChannel,
+		ReceiveEvent: receiveChannel,
+		TypeRegistry: registry,
+	}
+	if err := receiver.ReceiveEvents(options); err != nil {
+		t.Fatal(err)
+	}
+
+	// Give the receiver's GET a moment to connect before the publisher fans anything out.
+	time.Sleep(100 * time.Millisecond)
+
+	published := cqrs.VersionedEvent{
+		ID:        "1",
+		EventType: "order.Created",
+		SourceID:  "order-1",
+		Event:     orderCreated{OrderID: "order-1"},
+	}
+	if err := publisher.PublishEvents([]cqrs.VersionedEvent{published}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case accept := <-receiveChannel:
+		decoded, ok := accept.Event.Event.(orderCreated)
+		if !ok {
+			t.Fatalf("expected Event to decode as orderCreated, got %T", accept.Event.Event)
+		}
+
+		if decoded.OrderID != "order-1" {
+			t.Fatalf("expected OrderID %q, got %q", "order-1", decoded.OrderID)
+		}
+
+		accept.ProcessedSuccessfully <- true
+	case err := <-errorChannel:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the event to arrive over SSE")
+	}
+}
@@ -1,43 +1,197 @@
 package cqrs
 
-// InMemoryEventBus provides an inmemory implementation of the VersionedEventPublisher VersionedEventReceiver interfaces
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrEventBusSubscriberQueueFull is returned by PublishEvents when a subscriber's queue is full and the
+// bus was configured with the ReturnError overflow policy.
+var ErrEventBusSubscriberQueueFull = errors.New("cqrs: subscriber queue full")
+
+// EventBusOverflowPolicy controls what InMemoryEventBus does when a subscriber's buffered queue is full.
+type EventBusOverflowPolicy int
+
+const (
+	// Block waits for the subscriber to drain its queue before publishing the next event. This is the
+	// default and matches the bus's original unbuffered, single-subscriber behaviour.
+	Block EventBusOverflowPolicy = iota
+	// DropOldest discards the oldest buffered event to make room for the new one.
+	DropOldest
+	// ReturnError fails PublishEvents immediately instead of blocking or dropping.
+	ReturnError
+)
+
+// InMemoryEventBusOptions configures a NewInMemoryEventBusWithOptions instance.
+type InMemoryEventBusOptions struct {
+	// QueueSize is the per-subscriber buffer size. Zero means unbuffered.
+	QueueSize int
+	// OverflowPolicy controls what happens when a subscriber's queue is full.
+	OverflowPolicy EventBusOverflowPolicy
+}
+
+// eventBusSubscriber is a single broadcaster subscription. done is closed by Unsubscribe and is never the
+// events channel itself, so a PublishEvents call already blocked delivering to this subscriber aborts
+// instead of sending on (or racing the close of) a channel another goroutine is reading from.
+type eventBusSubscriber struct {
+	events chan VersionedEvent
+	done   chan struct{}
+}
+
+// InMemoryEventBus provides an inmemory implementation of the VersionedEventPublisher VersionedEventReceiver
+// interfaces. It broadcasts every published event to every current subscriber, so replay consumers,
+// read-model projectors and integration-logger consumers can all attach to the same bus independently.
 type InMemoryEventBus struct {
-	publishedEventsChannel chan VersionedEvent
-	startReceiving         bool
+	options InMemoryEventBusOptions
+
+	mu                 sync.Mutex
+	nextSubscriptionID uint64
+	subscribers        map[uint64]*eventBusSubscriber
 }
 
-// NewInMemoryEventBus constructor
+// NewInMemoryEventBus constructor. Subscribers are unbuffered and PublishEvents blocks until each has
+// consumed the event, matching the bus's original single-subscriber behaviour.
 func NewInMemoryEventBus() *InMemoryEventBus {
-	publishedEventsChannel := make(chan VersionedEvent, 0)
-	return &InMemoryEventBus{publishedEventsChannel, false}
+	return NewInMemoryEventBusWithOptions(InMemoryEventBusOptions{})
 }
 
-// PublishEvents publishes events to the event bus
+// NewInMemoryEventBusWithOptions constructs an InMemoryEventBus with explicit per-subscriber queueing and
+// overflow behaviour.
+func NewInMemoryEventBusWithOptions(options InMemoryEventBusOptions) *InMemoryEventBus {
+	return &InMemoryEventBus{options: options, subscribers: make(map[uint64]*eventBusSubscriber)}
+}
+
+// Subscribe registers a new independent subscriber and returns its id along with the channel events are
+// broadcast on. ReceiveEvents calls Subscribe internally, so most callers never need to call it directly;
+// it exists for consumers (replay readers, projectors, loggers) that want to attach to the bus without
+// going through the VersionedEventReceiver/dispatch-manager path. The returned channel is never closed;
+// callers must stop reading from it once they call Unsubscribe rather than relying on a range/close signal.
+func (bus *InMemoryEventBus) Subscribe() (id uint64, events <-chan VersionedEvent) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.nextSubscriptionID++
+	id = bus.nextSubscriptionID
+	sub := &eventBusSubscriber{events: make(chan VersionedEvent, bus.options.QueueSize), done: make(chan struct{})}
+	bus.subscribers[id] = sub
+
+	return id, sub.events
+}
+
+// Unsubscribe detaches a subscriber so PublishEvents stops fanning events out to it. It only removes the
+// subscriber from the map and signals its done channel - it deliberately never closes the events channel,
+// since a concurrent PublishEvents could still be blocked sending to it.
+func (bus *InMemoryEventBus) Unsubscribe(id uint64) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	if sub, ok := bus.subscribers[id]; ok {
+		delete(bus.subscribers, id)
+		close(sub.done)
+	}
+}
+
+// PublishEvents publishes events to the event bus, fanning each one out to every current subscriber
+// independently. With no subscribers attached this is a no-op.
 func (bus *InMemoryEventBus) PublishEvents(events []VersionedEvent) error {
-	if !bus.startReceiving {
-		return nil
+	bus.mu.Lock()
+	subscribers := make([]*eventBusSubscriber, 0, len(bus.subscribers))
+	for _, sub := range bus.subscribers {
+		subscribers = append(subscribers, sub)
 	}
+	bus.mu.Unlock()
 
 	for _, event := range events {
-		bus.publishedEventsChannel <- event
+		logger().Debug("InMemoryEventBus.PublishEvents", map[string]interface{}{
+			"event_type":     event.EventType,
+			"source_id":      event.SourceID,
+			"version":        event.Version,
+			"correlation_id": event.CorrelationID,
+		})
+
+		for _, sub := range subscribers {
+			if err := bus.deliver(sub, event); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
-// ReceiveEvents starts a go routine that monitors incoming events and routes them to a receiver channel specified within the options
+// deliver sends event to a single subscriber's queue, applying the bus's configured overflow policy. It
+// always also selects on sub.done, so a subscriber that unsubscribes mid-delivery makes deliver return
+// cleanly instead of blocking forever or sending to an abandoned channel.
+func (bus *InMemoryEventBus) deliver(sub *eventBusSubscriber, event VersionedEvent) error {
+	switch bus.options.OverflowPolicy {
+	case DropOldest:
+		for {
+			select {
+			case sub.events <- event:
+				return nil
+			case <-sub.done:
+				return nil
+			default:
+				select {
+				case <-sub.events:
+				default:
+				}
+			}
+		}
+	case ReturnError:
+		select {
+		case sub.events <- event:
+			return nil
+		case <-sub.done:
+			return nil
+		default:
+			return ErrEventBusSubscriberQueueFull
+		}
+	default:
+		select {
+		case sub.events <- event:
+			return nil
+		case <-sub.done:
+			return nil
+		}
+	}
+}
+
+// ReceiveEvents starts a go routine, named "InMemoryEventBus.Receiver" in logs, that subscribes to the bus
+// and routes broadcast events to a receiver channel specified within the options. The go routine is bound
+// to options.Context: it unsubscribes and exits as soon as the context is cancelled, regardless of where
+// it is blocked.
 func (bus *InMemoryEventBus) ReceiveEvents(options VersionedEventReceiverOptions) error {
-	bus.startReceiving = true
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	id, events := bus.Subscribe()
 
 	go func() {
+		logger().Info("InMemoryEventBus.Receiver: starting", nil)
+		defer logger().Info("InMemoryEventBus.Receiver: stopped", nil)
+		defer bus.Unsubscribe(id)
+
 		for {
 			select {
-			case ch := <-options.Close:
-				ch <- nil
-			case versionedEvent := <-bus.publishedEventsChannel:
+			case <-ctx.Done():
+				return
+			case versionedEvent := <-events:
 				ackCh := make(chan bool)
-				options.ReceiveEvent <- VersionedEventTransactedAccept{versionedEvent, ackCh}
-				<-ackCh
+				select {
+				case options.ReceiveEvent <- VersionedEventTransactedAccept{versionedEvent, ackCh}:
+				case <-ctx.Done():
+					return
+				}
+
+				select {
+				case <-ackCh:
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}()
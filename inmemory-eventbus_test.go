@@ -0,0 +1,115 @@
+package cqrs_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/andrewwebber/cqrs"
+)
+
+func TestInMemoryEventBusBroadcastsToEverySubscriber(t *testing.T) {
+	bus := cqrs.NewInMemoryEventBus()
+
+	_, firstEvents := bus.Subscribe()
+	_, secondEvents := bus.Subscribe()
+
+	published := cqrs.VersionedEvent{SourceID: "order-1", EventType: "order.Created"}
+	go func() {
+		if err := bus.PublishEvents([]cqrs.VersionedEvent{published}); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	timeout := time.After(5 * time.Second)
+
+	// PublishEvents visits subscribers in no fixed order and blocks on each unbuffered one in turn, so both
+	// channels are read concurrently; a channel is set to nil once it has delivered.
+	for firstEvents != nil || secondEvents != nil {
+		select {
+		case event := <-firstEvents:
+			if event.SourceID != published.SourceID {
+				t.Fatalf("expected %v, got %v", published, event)
+			}
+			firstEvents = nil
+		case event := <-secondEvents:
+			if event.SourceID != published.SourceID {
+				t.Fatalf("expected %v, got %v", published, event)
+			}
+			secondEvents = nil
+		case <-timeout:
+			if firstEvents != nil {
+				t.Fatal("first subscriber did not receive the event")
+			}
+			t.Fatal("second subscriber did not receive the event")
+		}
+	}
+}
+
+func TestInMemoryEventBusUnsubscribeStopsDelivery(t *testing.T) {
+	bus := cqrs.NewInMemoryEventBusWithOptions(cqrs.InMemoryEventBusOptions{QueueSize: 1})
+
+	id, events := bus.Subscribe()
+	bus.Unsubscribe(id)
+
+	if err := bus.PublishEvents([]cqrs.VersionedEvent{{SourceID: "order-1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event, ok := <-events:
+		if ok {
+			t.Fatalf("expected no further events after Unsubscribe, got %v", event)
+		}
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestInMemoryEventBusUnsubscribeDuringBlockedPublishDoesNotPanic(t *testing.T) {
+	bus := cqrs.NewInMemoryEventBus()
+	id, _ := bus.Subscribe()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("PublishEvents panicked: %v", r)
+				return
+			}
+		}()
+
+		done <- bus.PublishEvents([]cqrs.VersionedEvent{{SourceID: "order-1"}})
+	}()
+
+	// Give PublishEvents a moment to block delivering to the unbuffered subscriber before it is
+	// unsubscribed concurrently - this is the exact race that used to panic with "send on closed channel".
+	time.Sleep(50 * time.Millisecond)
+	bus.Unsubscribe(id)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for PublishEvents to return after a concurrent Unsubscribe")
+	}
+}
+
+func TestInMemoryEventBusReturnErrorOverflowPolicy(t *testing.T) {
+	bus := cqrs.NewInMemoryEventBusWithOptions(cqrs.InMemoryEventBusOptions{
+		QueueSize:      1,
+		OverflowPolicy: cqrs.ReturnError,
+	})
+
+	bus.Subscribe()
+
+	event := cqrs.VersionedEvent{SourceID: "order-1"}
+	if err := bus.PublishEvents([]cqrs.VersionedEvent{event}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bus.PublishEvents([]cqrs.VersionedEvent{event}); err != cqrs.ErrEventBusSubscriberQueueFull {
+		t.Fatalf("expected ErrEventBusSubscriberQueueFull, got %v", err)
+	}
+}
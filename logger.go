@@ -0,0 +1,59 @@
+package cqrs
+
+import "sync"
+
+// Logger is the structured logging abstraction used throughout the package so callers can route cqrs
+// log output through their own logging stack (logrus, zap, zerolog, ...) instead of the stdlib log
+// package.
+type Logger interface {
+	Debug(msg string, fields map[string]interface{})
+	Info(msg string, fields map[string]interface{})
+	Error(msg string, fields map[string]interface{}, err error)
+}
+
+// logHandlerFunc adapts a single function into a Logger so SetLogHandler callers don't have to
+// implement all three methods.
+type logHandlerFunc func(msg string, fields map[string]interface{}, err error)
+
+func (f logHandlerFunc) Debug(msg string, fields map[string]interface{}) { f(msg, fields, nil) }
+func (f logHandlerFunc) Info(msg string, fields map[string]interface{})  { f(msg, fields, nil) }
+func (f logHandlerFunc) Error(msg string, fields map[string]interface{}, err error) {
+	f(msg, fields, err)
+}
+
+func noopLogHandler(msg string, fields map[string]interface{}, err error) {}
+
+var (
+	loggerMu     sync.RWMutex
+	activeLogger Logger = logHandlerFunc(noopLogHandler)
+)
+
+// SetLogHandler installs fn as the package-wide log sink. Every Debug/Info/Error call made by
+// VersionedEventDispatchManager, InMemoryEventBus and cqrs/httpsse (via ActiveLogger) is routed through
+// it, so callers can adapt logrus/zap/zerolog without the package taking a hard dependency on any of them.
+// Command bus code does not log through it.
+// Passing nil restores the default no-op handler.
+func SetLogHandler(fn func(msg string, fields map[string]interface{}, err error)) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+
+	if fn == nil {
+		fn = noopLogHandler
+	}
+
+	activeLogger = logHandlerFunc(fn)
+}
+
+// logger returns the currently installed Logger.
+func logger() Logger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	return activeLogger
+}
+
+// ActiveLogger returns the Logger currently installed via SetLogHandler (or the default no-op), so
+// packages built on top of cqrs - such as cqrs/httpsse - can log through the same sink instead of
+// introducing a second, unrelated one.
+func ActiveLogger() Logger {
+	return logger()
+}
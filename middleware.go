@@ -0,0 +1,76 @@
+package cqrs
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RecoverMiddleware converts a panicking handler into a returned error so a single bad handler can't kill
+// the Listen/ListenContext loop; the event's ack channel still receives false for that event.
+func RecoverMiddleware() VersionedEventMiddleware {
+	return func(next VersionedEventHandler) VersionedEventHandler {
+		return func(event VersionedEvent) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("cqrs: handler panic: %v", r)
+				}
+			}()
+
+			return next(event)
+		}
+	}
+}
+
+// TimeoutMiddleware fails a handler invocation with context.DeadlineExceeded if it hasn't returned within
+// d. The handler is given no cancellation signal and Go can't preempt a running goroutine, so a handler
+// that overruns d keeps running in the background after the timeout fires and its result is discarded.
+func TimeoutMiddleware(d time.Duration) VersionedEventMiddleware {
+	return func(next VersionedEventHandler) VersionedEventHandler {
+		return func(event VersionedEvent) error {
+			done := make(chan error, 1)
+			go func() { done <- next(event) }()
+
+			select {
+			case err := <-done:
+				return err
+			case <-time.After(d):
+				return context.DeadlineExceeded
+			}
+		}
+	}
+}
+
+// RetryMiddleware retries a failing handler up to n additional times, waiting backoff(attempt) between
+// attempts, where attempt is the 1-based retry number. It returns the last error if every attempt fails.
+func RetryMiddleware(n int, backoff func(attempt int) time.Duration) VersionedEventMiddleware {
+	return func(next VersionedEventHandler) VersionedEventHandler {
+		return func(event VersionedEvent) error {
+			var err error
+			for attempt := 0; attempt <= n; attempt++ {
+				if attempt > 0 {
+					time.Sleep(backoff(attempt))
+				}
+
+				if err = next(event); err == nil {
+					return nil
+				}
+			}
+
+			return err
+		}
+	}
+}
+
+// MetricsMiddleware reports the duration and outcome of every handler invocation through record, so
+// callers can feed it into a Prometheus histogram/counter pair or similar.
+func MetricsMiddleware(record func(eventType string, dur time.Duration, err error)) VersionedEventMiddleware {
+	return func(next VersionedEventHandler) VersionedEventHandler {
+		return func(event VersionedEvent) error {
+			start := time.Now()
+			err := next(event)
+			record(event.EventType, time.Since(start), err)
+			return err
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package cqrs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andrewwebber/cqrs"
+)
+
+func TestRecoverMiddlewareConvertsPanicToError(t *testing.T) {
+	dispatcher := cqrs.NewVersionedEventDispatcher()
+	dispatcher.Use(cqrs.RecoverMiddleware())
+
+	dispatcher.RegisterGlobalHandler(func(event cqrs.VersionedEvent) error {
+		panic("boom")
+	})
+
+	if err := dispatcher.DispatchEvent(cqrs.VersionedEvent{}); err == nil {
+		t.Fatal("expected the panic to surface as an error")
+	}
+}
+
+func TestRetryMiddlewareRetriesUntilSuccess(t *testing.T) {
+	dispatcher := cqrs.NewVersionedEventDispatcher()
+	dispatcher.Use(cqrs.RetryMiddleware(2, func(attempt int) time.Duration { return time.Millisecond }))
+
+	attempts := 0
+	dispatcher.RegisterGlobalHandler(func(event cqrs.VersionedEvent) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("transient failure")
+		}
+
+		return nil
+	})
+
+	if err := dispatcher.DispatchEvent(cqrs.VersionedEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestMetricsMiddlewareRecordsEveryInvocation(t *testing.T) {
+	dispatcher := cqrs.NewVersionedEventDispatcher()
+
+	var recordedEventType string
+	var recordedErr error
+	dispatcher.Use(cqrs.MetricsMiddleware(func(eventType string, dur time.Duration, err error) {
+		recordedEventType = eventType
+		recordedErr = err
+	}))
+
+	expectedErr := errors.New("handler failure")
+	dispatcher.RegisterGlobalHandler(func(event cqrs.VersionedEvent) error {
+		return expectedErr
+	})
+
+	event := cqrs.VersionedEvent{EventType: "order.Created"}
+	if err := dispatcher.DispatchEvent(event); err != expectedErr {
+		t.Fatalf("expected %v, got %v", expectedErr, err)
+	}
+
+	if recordedEventType != event.EventType {
+		t.Fatalf("expected metrics for %q, got %q", event.EventType, recordedEventType)
+	}
+
+	if recordedErr != expectedErr {
+		t.Fatalf("expected recorded error %v, got %v", expectedErr, recordedErr)
+	}
+}
+
+func TestTimeoutMiddlewareReturnsDeadlineExceeded(t *testing.T) {
+	dispatcher := cqrs.NewVersionedEventDispatcher()
+	dispatcher.Use(cqrs.TimeoutMiddleware(10 * time.Millisecond))
+
+	dispatcher.RegisterGlobalHandler(func(event cqrs.VersionedEvent) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+
+	if err := dispatcher.DispatchEvent(cqrs.VersionedEvent{}); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package cqrs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// eventPatternHandler pairs a compiled glob matcher with the handler registered against it.
+type eventPatternHandler struct {
+	pattern string
+	matcher *regexp.Regexp
+	handler VersionedEventHandler
+}
+
+// CompileEventPattern compiles a dot-separated glob pattern into a regexp matched against
+// VersionedEvent.EventType. `*` matches a single segment (no `.`), `**` matches across any number
+// of segments and `?` matches a single character within a segment. Every other rune is literal.
+//
+// This is the same glob syntax RegisterEventHandlerPattern uses; exported so other packages (e.g.
+// cqrs/httpsse's ?pattern= query parameter) can filter events with it instead of a plain regexp, which
+// would otherwise silently accept an incompatible pattern language (e.g. "billing.**" is valid here but not
+// as a regexp).
+func CompileEventPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("cqrs: event pattern must not be empty")
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				expr.WriteString(".*")
+				i++
+			} else {
+				expr.WriteString("[^.]*")
+			}
+		case '?':
+			expr.WriteString("[^.]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+
+	expr.WriteString("$")
+
+	matcher, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("cqrs: malformed event pattern %q: %v", pattern, err)
+	}
+
+	return matcher, nil
+}
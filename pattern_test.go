@@ -0,0 +1,84 @@
+package cqrs_test
+
+import (
+	"testing"
+
+	"github.com/andrewwebber/cqrs"
+)
+
+type OrderCreated struct{}
+
+func TestMapBasedVersionedEventDispatcherPatternOverlap(t *testing.T) {
+	dispatcher := cqrs.NewVersionedEventDispatcher()
+
+	var exactCalled, patternCalled bool
+	dispatcher.RegisterEventHandler(OrderCreated{}, func(event cqrs.VersionedEvent) error {
+		exactCalled = true
+		return nil
+	})
+
+	if err := dispatcher.RegisterEventHandlerPattern("order.*", func(event cqrs.VersionedEvent) error {
+		patternCalled = true
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	event := cqrs.VersionedEvent{EventType: "order.Created", Event: OrderCreated{}}
+	if err := dispatcher.DispatchEvent(event); err != nil {
+		t.Fatal(err)
+	}
+
+	if !exactCalled {
+		t.Fatal("expected the exact type handler to fire")
+	}
+
+	if !patternCalled {
+		t.Fatal("expected the pattern handler to fire")
+	}
+}
+
+func TestMapBasedVersionedEventDispatcherPatternOrdering(t *testing.T) {
+	dispatcher := cqrs.NewVersionedEventDispatcher()
+
+	var calls []string
+	dispatcher.RegisterEventHandler(OrderCreated{}, func(event cqrs.VersionedEvent) error {
+		calls = append(calls, "exact")
+		return nil
+	})
+
+	if err := dispatcher.RegisterEventHandlerPattern("*.Created", func(event cqrs.VersionedEvent) error {
+		calls = append(calls, "pattern")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher.RegisterGlobalHandler(func(event cqrs.VersionedEvent) error {
+		calls = append(calls, "global")
+		return nil
+	})
+
+	event := cqrs.VersionedEvent{EventType: "order.Created", Event: OrderCreated{}}
+	if err := dispatcher.DispatchEvent(event); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"exact", "pattern", "global"}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, calls)
+	}
+
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, calls)
+		}
+	}
+}
+
+func TestRegisterEventHandlerPatternRejectsEmptyPattern(t *testing.T) {
+	dispatcher := cqrs.NewVersionedEventDispatcher()
+	if err := dispatcher.RegisterEventHandlerPattern("", func(event cqrs.VersionedEvent) error { return nil }); err == nil {
+		t.Fatal("expected an error for an empty pattern")
+	}
+}
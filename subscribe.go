@@ -0,0 +1,158 @@
+package cqrs
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// SubscribeOptions configures a correlation-scoped subscription created via
+// VersionedEventDispatchManager.Subscribe.
+type SubscribeOptions struct {
+	// CorrelationID restricts the subscription to events carrying this correlation ID. Empty matches
+	// every correlation ID.
+	CorrelationID string
+	// FromVersion excludes events with a lower Version than this.
+	FromVersion int
+	// EventTypes restricts the subscription to these EventType values. Empty matches every event type.
+	EventTypes []string
+	// Replay, when true, drains already-published events for CorrelationID from the manager's
+	// VersionedEventPublicationLogger before the subscription switches to live events.
+	Replay bool
+}
+
+// Subscribe returns a channel carrying every live VersionedEvent dispatched through Listen/ListenContext
+// that matches opts, optionally preceded by a replay of already-published events for the same correlation
+// ID. This gives read-model rebuilders and saga processors a single "catch up then follow" primitive
+// instead of manually composing a VersionedEventPublicationLogger and a VersionedEventReceiver.
+//
+// When opts.Replay is true and a VersionedEventPublicationLogger has been attached via
+// SetPublicationLogger, replayed events are drained from GetIntegrationEventsByCorrelationID (sorted via
+// ByCreated) before live events start flowing. The global handler backing this subscription is registered
+// with the dispatcher before replay begins, so a live event published mid-replay is never missed; while
+// replay is in progress such an event is buffered rather than delivered out of order, and the buffer is
+// flushed the moment replay completes. Events are deduplicated by VersionedEvent.ID across replay and live
+// delivery, so an event seen during replay is never delivered again live.
+//
+// Cancelling ctx closes the returned channel and deregisters the subscription's dispatcher handler, so a
+// caller that cancels ctx never leaks a handler registration.
+func (m *VersionedEventDispatchManager) Subscribe(ctx context.Context, opts SubscribeOptions) (<-chan VersionedEvent, error) {
+	out := make(chan VersionedEvent)
+
+	var mu sync.Mutex
+	seen := make(map[string]struct{})
+	replaying := opts.Replay && m.publicationLogger != nil
+	var pending []VersionedEvent
+
+	// alreadySeen reports whether event.ID has been delivered before and marks it seen if not. Callers
+	// must hold mu.
+	alreadySeen := func(event VersionedEvent) bool {
+		if _, ok := seen[event.ID]; ok {
+			return true
+		}
+
+		seen[event.ID] = struct{}{}
+		return false
+	}
+
+	handlerID := m.versionedEventDispatcher.RegisterGlobalHandler(func(event VersionedEvent) error {
+		if !subscriptionMatches(opts, event) {
+			return nil
+		}
+
+		mu.Lock()
+		if alreadySeen(event) {
+			mu.Unlock()
+			return nil
+		}
+
+		if replaying {
+			pending = append(pending, event)
+			mu.Unlock()
+			return nil
+		}
+		mu.Unlock()
+
+		select {
+		case out <- event:
+		case <-ctx.Done():
+		}
+
+		return nil
+	})
+
+	go func() {
+		defer close(out)
+		defer m.versionedEventDispatcher.RemoveGlobalHandler(handlerID)
+
+		if replaying {
+			replayed, err := m.publicationLogger.GetIntegrationEventsByCorrelationID(opts.CorrelationID)
+			if err != nil {
+				logger().Error("VersionedEventDispatchManager.Subscribe replay failed", map[string]interface{}{"correlation_id": opts.CorrelationID}, err)
+				replayed = nil
+			}
+			sort.Sort(ByCreated(replayed))
+
+			for _, event := range replayed {
+				if !subscriptionMatches(opts, event) {
+					continue
+				}
+
+				mu.Lock()
+				skip := alreadySeen(event)
+				mu.Unlock()
+				if skip {
+					continue
+				}
+
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			mu.Lock()
+			flushed := pending
+			pending = nil
+			replaying = false
+			mu.Unlock()
+
+			for _, event := range flushed {
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		<-ctx.Done()
+	}()
+
+	return out, nil
+}
+
+// subscriptionMatches reports whether event satisfies opts's correlation ID, version floor and event type
+// filters.
+func subscriptionMatches(opts SubscribeOptions, event VersionedEvent) bool {
+	if opts.CorrelationID != "" && event.CorrelationID != opts.CorrelationID {
+		return false
+	}
+
+	if event.Version < opts.FromVersion {
+		return false
+	}
+
+	if len(opts.EventTypes) == 0 {
+		return true
+	}
+
+	for _, eventType := range opts.EventTypes {
+		if eventType == event.EventType {
+			return true
+		}
+	}
+
+	return false
+}
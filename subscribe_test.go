@@ -0,0 +1,231 @@
+package cqrs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andrewwebber/cqrs"
+)
+
+// scriptedReceiver is a VersionedEventReceiver test double that hands its options back to the test so
+// live events can be pushed through ReceiveEvent on demand.
+type scriptedReceiver struct {
+	options chan cqrs.VersionedEventReceiverOptions
+}
+
+func newScriptedReceiver() *scriptedReceiver {
+	return &scriptedReceiver{options: make(chan cqrs.VersionedEventReceiverOptions, 1)}
+}
+
+func (r *scriptedReceiver) ReceiveEvents(options cqrs.VersionedEventReceiverOptions) error {
+	r.options <- options
+	return nil
+}
+
+// fakePublicationLogger is a VersionedEventPublicationLogger test double returning a fixed set of events
+// for GetIntegrationEventsByCorrelationID.
+type fakePublicationLogger struct {
+	byCorrelationID map[string][]cqrs.VersionedEvent
+}
+
+func (l *fakePublicationLogger) SaveIntegrationEvent(cqrs.VersionedEvent) error { return nil }
+
+func (l *fakePublicationLogger) AllIntegrationEventsEverPublished() ([]cqrs.VersionedEvent, error) {
+	return nil, nil
+}
+
+func (l *fakePublicationLogger) GetIntegrationEventsByCorrelationID(correlationID string) ([]cqrs.VersionedEvent, error) {
+	return l.byCorrelationID[correlationID], nil
+}
+
+// blockingPublicationLogger holds GetIntegrationEventsByCorrelationID open until release is closed, so a
+// test can deliver a live event while replay is still in flight. It signals replayStarted the moment it is
+// called, so the test knows it is safe to push the live event.
+type blockingPublicationLogger struct {
+	replayStarted chan struct{}
+	release       chan struct{}
+	events        []cqrs.VersionedEvent
+}
+
+func (l *blockingPublicationLogger) SaveIntegrationEvent(cqrs.VersionedEvent) error { return nil }
+
+func (l *blockingPublicationLogger) AllIntegrationEventsEverPublished() ([]cqrs.VersionedEvent, error) {
+	return nil, nil
+}
+
+func (l *blockingPublicationLogger) GetIntegrationEventsByCorrelationID(correlationID string) ([]cqrs.VersionedEvent, error) {
+	close(l.replayStarted)
+	<-l.release
+	return l.events, nil
+}
+
+func startListening(t *testing.T, manager *cqrs.VersionedEventDispatchManager, receiver *scriptedReceiver) (cqrs.VersionedEventReceiverOptions, func()) {
+	t.Helper()
+
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	go manager.ListenContext(listenCtx, false)
+
+	select {
+	case options := <-receiver.options:
+		return options, stopListening
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the manager to start the receiver")
+		return cqrs.VersionedEventReceiverOptions{}, stopListening
+	}
+}
+
+func pushLiveEvent(t *testing.T, options cqrs.VersionedEventReceiverOptions, event cqrs.VersionedEvent) {
+	t.Helper()
+
+	ack := make(chan bool)
+	options.ReceiveEvent <- cqrs.VersionedEventTransactedAccept{Event: event, ProcessedSuccessfully: ack}
+
+	select {
+	case <-ack:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the dispatch manager to acknowledge the live event")
+	}
+}
+
+func TestSubscribeReplaysThenFollowsLiveWithoutSkippingOrDuplicating(t *testing.T) {
+	eventA := cqrs.VersionedEvent{ID: "1", CorrelationID: "abc", Version: 1, Created: time.Unix(1, 0)}
+	eventB := cqrs.VersionedEvent{ID: "2", CorrelationID: "abc", Version: 2, Created: time.Unix(2, 0)}
+	eventC := cqrs.VersionedEvent{ID: "3", CorrelationID: "abc", Version: 3, Created: time.Unix(3, 0)}
+
+	receiver := newScriptedReceiver()
+	manager := cqrs.NewVersionedEventDispatchManager(receiver, nil)
+	manager.SetPublicationLogger(&fakePublicationLogger{
+		byCorrelationID: map[string][]cqrs.VersionedEvent{"abc": {eventB, eventA}},
+	})
+
+	receiverOptions, stopListening := startListening(t, manager, receiver)
+	defer stopListening()
+
+	subCtx, unsubscribe := context.WithCancel(context.Background())
+	defer unsubscribe()
+
+	events, err := manager.Subscribe(subCtx, cqrs.SubscribeOptions{CorrelationID: "abc", Replay: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receiveNext := func() cqrs.VersionedEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a subscription event")
+			return cqrs.VersionedEvent{}
+		}
+	}
+
+	// Replay is sorted via ByCreated, so eventA arrives before eventB despite the logger returning them
+	// out of order.
+	if first := receiveNext(); first.ID != eventA.ID {
+		t.Fatalf("expected replay to start with %v, got %v", eventA, first)
+	}
+
+	if second := receiveNext(); second.ID != eventB.ID {
+		t.Fatalf("expected replay to continue with %v, got %v", eventB, second)
+	}
+
+	// The live stream redelivers eventB (as if it arrived twice in flight) before the new eventC. The
+	// duplicate must be suppressed and eventC must still come through. eventC's delivery blocks on a
+	// reader, so it is pushed from a goroutine and drained by receiveNext below.
+	pushLiveEvent(t, receiverOptions, eventB)
+
+	liveDone := make(chan struct{})
+	go func() {
+		defer close(liveDone)
+		pushLiveEvent(t, receiverOptions, eventC)
+	}()
+
+	if third := receiveNext(); third.ID != eventC.ID {
+		t.Fatalf("expected the live eventC, got %v", third)
+	}
+
+	select {
+	case <-liveDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the live eventC push to be acknowledged")
+	}
+
+	select {
+	case unexpected := <-events:
+		t.Fatalf("expected no further events, got %v", unexpected)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// TestSubscribeBuffersLiveEventDeliveredMidReplay kills the assumption that replay and live delivery never
+// overlap: it holds GetIntegrationEventsByCorrelationID open and pushes a live event while replay is still
+// running. The live event must not be skipped, duplicated, or interleaved ahead of the in-flight replay -
+// it is expected strictly after every replayed event.
+func TestSubscribeBuffersLiveEventDeliveredMidReplay(t *testing.T) {
+	eventA := cqrs.VersionedEvent{ID: "1", CorrelationID: "abc", Version: 1, Created: time.Unix(1, 0)}
+	eventB := cqrs.VersionedEvent{ID: "2", CorrelationID: "abc", Version: 2, Created: time.Unix(2, 0)}
+	liveEvent := cqrs.VersionedEvent{ID: "3", CorrelationID: "abc", Version: 3, Created: time.Unix(3, 0)}
+
+	receiver := newScriptedReceiver()
+	manager := cqrs.NewVersionedEventDispatchManager(receiver, nil)
+
+	logger := &blockingPublicationLogger{
+		replayStarted: make(chan struct{}),
+		release:       make(chan struct{}),
+		events:        []cqrs.VersionedEvent{eventA, eventB},
+	}
+	manager.SetPublicationLogger(logger)
+
+	receiverOptions, stopListening := startListening(t, manager, receiver)
+	defer stopListening()
+
+	subCtx, unsubscribe := context.WithCancel(context.Background())
+	defer unsubscribe()
+
+	events, err := manager.Subscribe(subCtx, cqrs.SubscribeOptions{CorrelationID: "abc", Replay: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-logger.replayStarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for replay to start")
+	}
+
+	// Deliver the live event while GetIntegrationEventsByCorrelationID is still blocked. Since replay
+	// hasn't handed anything to the subscriber yet, the global handler must buffer this rather than send
+	// it to the (so-far unread) out channel, so this ack must come back without requiring a reader.
+	pushLiveEvent(t, receiverOptions, liveEvent)
+
+	close(logger.release)
+
+	receiveNext := func() cqrs.VersionedEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a subscription event")
+			return cqrs.VersionedEvent{}
+		}
+	}
+
+	if first := receiveNext(); first.ID != eventA.ID {
+		t.Fatalf("expected replay to start with %v, got %v", eventA, first)
+	}
+
+	if second := receiveNext(); second.ID != eventB.ID {
+		t.Fatalf("expected replay to continue with %v, got %v", eventB, second)
+	}
+
+	if third := receiveNext(); third.ID != liveEvent.ID {
+		t.Fatalf("expected the buffered live event after replay, got %v", third)
+	}
+
+	select {
+	case unexpected := <-events:
+		t.Fatalf("expected no further events, got %v", unexpected)
+	case <-time.After(200 * time.Millisecond):
+	}
+}